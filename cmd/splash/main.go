@@ -0,0 +1,79 @@
+// Command splash downloads and assembles a Fortnite build from Epic's
+// chunked manifest/CDN distribution format. It's a thin CLI around the
+// splash package; see that package for programmatic use.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/Cubed/splash/splash"
+)
+
+func main() {
+	d := splash.NewDownloader()
+
+	manifestID := flag.String("manifest", "", "download a specific manifest")
+	fileFilter := flag.String("files", "", "only download specific files")
+	dlUrls := flag.String("url", "", "comma-separated download URLs (defaults to Epic's CDN)")
+	skipIntegrityCheck := flag.Bool("skipcheck", false, "skip file integrity check")
+	jsonProgress := flag.Bool("json-progress", false, "write progress as newline-delimited JSON to stderr instead of a terminal status line")
+
+	flag.StringVar(&d.Platform, "platform", "Windows", "platform to download for")
+	flag.StringVar(&d.InstallPath, "install-dir", "files", "install path")
+	flag.StringVar(&d.CachePath, "cache", "cache", "cache path")
+	flag.IntVar(&d.Workers, "workers", 4, "number of concurrent chunk download workers")
+	flag.BoolVar(&d.Repair, "repair", false, "verify existing files chunk-by-chunk and only re-download the chunks that are corrupt")
+	flag.Int64Var(&d.ChunkCacheSize, "chunk-cache-size", 0, "bytes of reused chunks to keep on disk under cachePath/chunks (0 keeps them in memory instead)")
+	flag.Parse()
+
+	if *jsonProgress {
+		d.Progress = splash.JSONProgress(os.Stderr)
+	} else {
+		d.Progress = splash.TerminalProgress(os.Stderr)
+	}
+
+	if *dlUrls != "" {
+		d.DownloadURLs = strings.Split(*dlUrls, ",")
+	}
+
+	var filter []string
+	if *fileFilter != "" {
+		filter = strings.Split(*fileFilter, ",")
+	}
+
+	ctx := context.Background()
+
+	catalog, err := d.LoadCatalog()
+	if err != nil {
+		log.Fatalf("Failed to load catalog: %v", err)
+	}
+	log.Printf("Catalog %s (%s) %s loaded.\n", catalog.Elements[0].AppName, catalog.Elements[0].LabelName, catalog.Elements[0].BuildVersion)
+
+	manifest, err := d.LoadManifest(catalog, *manifestID)
+	if err != nil {
+		log.Fatalf("Failed to load manifest: %v", err)
+	}
+	log.Printf("Manifest %s %s loaded.\n", manifest.AppNameString, manifest.BuildVersionString)
+
+	if err := d.DownloadFiles(ctx, manifest, filter); err != nil {
+		log.Fatalf("Download failed: %v", err)
+	}
+
+	if !*skipIntegrityCheck {
+		log.Println("Verifying file integrity...")
+
+		fileErrors, err := d.VerifyFiles(ctx, manifest, filter)
+		if err != nil {
+			log.Fatalf("Verification aborted: %v", err)
+		}
+		for _, fe := range fileErrors {
+			log.Printf("File %s is corrupt - %v\n", fe.FileName, fe.Err)
+		}
+	}
+
+	log.Println("Done!")
+}
@@ -0,0 +1,123 @@
+package splash
+
+import (
+	"fmt"
+	"sync"
+)
+
+// chunkWindow is the byte window of a chunk a FileChunkPart actually uses.
+type chunkWindow struct {
+	offset uint32
+	size   uint32
+}
+
+// chunkPool coordinates handoff of decompressed chunks between workers and
+// the file assembly loop. The bytes themselves live in a ChunkStore (in
+// memory or on disk); chunkPool pins every GUID it's told about (via
+// ChunkStore.Pin) so capacity-driven eviction can't reclaim a chunk the
+// assembly loop hasn't consumed yet.
+//
+// put must never block waiting for store capacity. Workers race ahead of
+// the assembly loop and can finish chunks in an arbitrary order relative to
+// the order the loop needs them in - if a full store made put() block, a
+// handful of chunks finishing out of order could fill it up and wedge the
+// worker handling the one chunk everything else is waiting on, hanging the
+// whole run. Pinning (and letting the store run over budget rather than
+// evict something pinned) is what makes it safe for put() to always
+// succeed immediately instead.
+type chunkPool struct {
+	store ChunkStore
+
+	mu           sync.Mutex
+	errs         map[string]error
+	rangeFetched map[string]bool
+	ready        map[string]chan struct{}
+}
+
+func newChunkPool(guids []string, store ChunkStore) *chunkPool {
+	p := &chunkPool{
+		store:        store,
+		errs:         make(map[string]error),
+		rangeFetched: make(map[string]bool),
+		ready:        make(map[string]chan struct{}, len(guids)),
+	}
+	for _, guid := range guids {
+		p.ready[guid] = make(chan struct{})
+		store.Pin(guid)
+	}
+	return p
+}
+
+// put stores a freshly downloaded chunk and marks it ready.
+func (p *chunkPool) put(guid string, data []byte) {
+	p.store.Put(guid, data)
+	close(p.ready[guid])
+}
+
+// putRange stores a chunk that was fetched as a partial byte range rather
+// than in full, so the assembly loop knows not to re-seek into it.
+func (p *chunkPool) putRange(guid string, data []byte) {
+	p.mu.Lock()
+	p.rangeFetched[guid] = true
+	p.mu.Unlock()
+
+	p.put(guid, data)
+}
+
+// wasRangeFetched reports whether guid's data is a range-fetched window
+// rather than the full decompressed chunk.
+func (p *chunkPool) wasRangeFetched(guid string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.rangeFetched[guid]
+}
+
+// publishExisting marks a chunk ready that was already found in the store
+// (e.g. left over on disk from a previous run), without re-writing it.
+func (p *chunkPool) publishExisting(guid string) {
+	close(p.ready[guid])
+}
+
+// fail records that a chunk could not be downloaded; waiters are unblocked
+// with the error instead of data. The GUID never gets a Put, so its pin is
+// cleared here instead of by evict.
+func (p *chunkPool) fail(guid string, err error) {
+	p.mu.Lock()
+	p.errs[guid] = err
+	p.mu.Unlock()
+
+	p.store.Release(guid)
+	close(p.ready[guid])
+}
+
+// wait blocks until guid is available and returns its data, or the error
+// that prevented it from downloading.
+func (p *chunkPool) wait(guid string) ([]byte, error) {
+	<-p.ready[guid]
+
+	p.mu.Lock()
+	err, failed := p.errs[guid]
+	p.mu.Unlock()
+	if failed {
+		return nil, err
+	}
+
+	data, ok := p.store.Get(guid)
+	if !ok {
+		return nil, fmt.Errorf("chunk %s: marked ready but missing from store", guid)
+	}
+	return data, nil
+}
+
+// evict drops a chunk once it has no remaining consumers, clearing its pin
+// so the store is free to reclaim its space.
+func (p *chunkPool) evict(guid string) {
+	p.mu.Lock()
+	_, failed := p.errs[guid]
+	p.mu.Unlock()
+	if failed {
+		return
+	}
+
+	p.store.Release(guid)
+}
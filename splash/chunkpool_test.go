@@ -0,0 +1,68 @@
+package splash
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// TestChunkPoolPutDoesNotBlockOnFullStore reproduces the scenario that used
+// to deadlock the whole run: a store with room for only as many chunks as
+// are already resident, and workers completing out of the order the
+// assembly loop needs them in. put must never block on store capacity -
+// see chunkPool's doc comment - or the worker handling the chunk the loop
+// is waiting on could hang behind ones that finished first.
+func TestChunkPoolPutDoesNotBlockOnFullStore(t *testing.T) {
+	store := newMemChunkStore(2)
+	pool := newChunkPool([]string{"filler1", "filler2", "needed"}, store)
+
+	done := make(chan struct{})
+	go func() {
+		// Two unrelated chunks finish first and fill the store...
+		pool.put("filler1", []byte("f1"))
+		pool.put("filler2", []byte("f2"))
+		// ...then the one the consumer is actually waiting on finishes. If
+		// put() blocked for room in the store, this would hang forever.
+		pool.put("needed", []byte("n"))
+		close(done)
+	}()
+
+	data, err := pool.wait("needed")
+	if err != nil {
+		t.Fatalf("wait(needed) returned error: %v", err)
+	}
+	if !bytes.Equal(data, []byte("n")) {
+		t.Errorf("wait(needed) = %q, want %q", data, "n")
+	}
+
+	<-done
+}
+
+func TestChunkPoolWaitErrorsOnStoreMiss(t *testing.T) {
+	store := newMemChunkStore(8)
+	pool := newChunkPool([]string{"guid1"}, store)
+
+	// Simulate a chunk marked ready without ever being stored, which
+	// should never happen in practice but must not surface as silent
+	// success if it does.
+	close(pool.ready["guid1"])
+
+	if _, err := pool.wait("guid1"); err == nil {
+		t.Errorf("expected wait() to return an error for a store miss")
+	}
+}
+
+func TestChunkPoolFailReleasesPin(t *testing.T) {
+	store := newMemChunkStore(8)
+	pool := newChunkPool([]string{"guid1"}, store)
+
+	wantErr := errors.New("download failed")
+	pool.fail("guid1", wantErr)
+
+	if _, err := pool.wait("guid1"); err != wantErr {
+		t.Errorf("wait(guid1) error = %v, want %v", err, wantErr)
+	}
+	if store.pinned["guid1"] {
+		t.Errorf("expected fail to clear guid1's pin")
+	}
+}
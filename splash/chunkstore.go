@@ -0,0 +1,238 @@
+package splash
+
+import (
+	"container/list"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ChunkStore abstracts where decompressed chunk bytes live between being
+// downloaded and being consumed by the file assembly loop. This lets a run
+// trade memory for disk (or vice versa) without touching the download or
+// assembly code, and lets callers substitute a custom ChunkStore (e.g. for
+// mocking in tests).
+type ChunkStore interface {
+	// Get returns the chunk's data and true if it is present in the store.
+	Get(guid string) ([]byte, bool)
+	// Put stores a chunk's data, making it available to later Gets.
+	Put(guid string, data []byte)
+	// Release drops a chunk once it has no remaining consumers.
+	Release(guid string)
+	// Pin marks a chunk as still wanted by a consumer that hasn't read it
+	// yet, so capacity-driven eviction must not reclaim it even if that
+	// means briefly running over budget. Without this, a producer that
+	// finishes a chunk out of order could evict one a slower worker is
+	// still being waited on for, or - worse, if eviction were instead
+	// implemented by blocking the producer - deadlock it behind a cache
+	// full of chunks nobody has consumed yet. Release clears the pin along
+	// with the chunk.
+	Pin(guid string)
+}
+
+// memChunkStore is a ChunkStore backed by a plain in-memory map, bounded to
+// maxEntries resident chunks via LRU eviction of unpinned entries.
+type memChunkStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	data       map[string][]byte
+	order      *list.List // front = most recently used
+	elems      map[string]*list.Element
+	pinned     map[string]bool
+}
+
+func newMemChunkStore(maxEntries int) *memChunkStore {
+	return &memChunkStore{
+		maxEntries: maxEntries,
+		data:       make(map[string][]byte),
+		order:      list.New(),
+		elems:      make(map[string]*list.Element),
+		pinned:     make(map[string]bool),
+	}
+}
+
+func (s *memChunkStore) Get(guid string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.data[guid]
+	if ok {
+		if elem, ok := s.elems[guid]; ok {
+			s.order.MoveToFront(elem)
+		}
+	}
+	return data, ok
+}
+
+func (s *memChunkStore) Put(guid string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data[guid] = data
+	if elem, ok := s.elems[guid]; ok {
+		s.order.MoveToFront(elem)
+	} else {
+		s.elems[guid] = s.order.PushFront(guid)
+	}
+
+	s.evictOverBudget()
+}
+
+func (s *memChunkStore) Release(guid string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pinned, guid)
+	s.remove(guid)
+}
+
+func (s *memChunkStore) Pin(guid string) {
+	s.mu.Lock()
+	s.pinned[guid] = true
+	s.mu.Unlock()
+}
+
+// evictOverBudget drops least-recently-used, unpinned chunks until the
+// resident count is back under maxEntries. A chunk that's still pinned is
+// left alone even if that means staying over budget - see ChunkStore.Pin.
+// Caller must hold s.mu.
+func (s *memChunkStore) evictOverBudget() {
+	if s.maxEntries <= 0 {
+		return
+	}
+	for elem := s.order.Back(); len(s.data) > s.maxEntries && elem != nil; {
+		guid := elem.Value.(string)
+		prev := elem.Prev()
+		if !s.pinned[guid] {
+			s.remove(guid)
+		}
+		elem = prev
+	}
+}
+
+// remove deletes a chunk's data and bookkeeping. Caller must hold s.mu.
+func (s *memChunkStore) remove(guid string) {
+	elem, ok := s.elems[guid]
+	if !ok {
+		return
+	}
+
+	delete(s.data, guid)
+	s.order.Remove(elem)
+	delete(s.elems, guid)
+}
+
+// diskChunkStore is a ChunkStore that writes decompressed chunks out to
+// dir/<guid> and, while resident usage is over maxBytes, evicts the least
+// recently used unpinned chunks. Pinned chunks are never evicted, so usage
+// can stay above maxBytes. Because the files survive process exit,
+// re-running splash after a crash reuses whatever was already fetched
+// instead of re-downloading it.
+type diskChunkStore struct {
+	mu       sync.Mutex
+	dir      string
+	maxBytes int64
+	curBytes int64
+	order    *list.List // front = most recently used
+	elems    map[string]*list.Element
+	sizes    map[string]int64
+	pinned   map[string]bool
+}
+
+func newDiskChunkStore(dir string, maxBytes int64) *diskChunkStore {
+	os.MkdirAll(dir, os.ModePerm)
+
+	return &diskChunkStore{
+		dir:      dir,
+		maxBytes: maxBytes,
+		order:    list.New(),
+		elems:    make(map[string]*list.Element),
+		sizes:    make(map[string]int64),
+		pinned:   make(map[string]bool),
+	}
+}
+
+func (s *diskChunkStore) path(guid string) string {
+	return filepath.Join(s.dir, guid)
+}
+
+func (s *diskChunkStore) Get(guid string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := ioutil.ReadFile(s.path(guid))
+	if err != nil {
+		return nil, false
+	}
+
+	if elem, ok := s.elems[guid]; ok {
+		s.order.MoveToFront(elem)
+	}
+
+	return data, true
+}
+
+func (s *diskChunkStore) Put(guid string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ioutil.WriteFile(s.path(guid), data, 0644); err != nil {
+		return
+	}
+
+	if elem, ok := s.elems[guid]; ok {
+		s.curBytes -= s.sizes[guid]
+		s.order.MoveToFront(elem)
+	} else {
+		s.elems[guid] = s.order.PushFront(guid)
+	}
+	s.sizes[guid] = int64(len(data))
+	s.curBytes += int64(len(data))
+
+	s.evictOverBudget()
+}
+
+func (s *diskChunkStore) Release(guid string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pinned, guid)
+	s.remove(guid)
+	// A chunk left on disk by a previous run was never Put in this one, so
+	// remove has no bookkeeping for it; delete its file directly.
+	os.Remove(s.path(guid))
+}
+
+func (s *diskChunkStore) Pin(guid string) {
+	s.mu.Lock()
+	s.pinned[guid] = true
+	s.mu.Unlock()
+}
+
+// evictOverBudget drops least-recently-used, unpinned chunks until curBytes
+// is back under maxBytes. A chunk that's still pinned is left alone even if
+// that means staying over budget - see ChunkStore.Pin. Caller must hold
+// s.mu.
+func (s *diskChunkStore) evictOverBudget() {
+	for elem := s.order.Back(); s.curBytes > s.maxBytes && elem != nil; {
+		guid := elem.Value.(string)
+		prev := elem.Prev()
+		if !s.pinned[guid] {
+			s.remove(guid)
+		}
+		elem = prev
+	}
+}
+
+// remove deletes a chunk's file and bookkeeping. Caller must hold s.mu.
+func (s *diskChunkStore) remove(guid string) {
+	elem, ok := s.elems[guid]
+	if !ok {
+		return
+	}
+
+	os.Remove(s.path(guid))
+	s.order.Remove(elem)
+	delete(s.elems, guid)
+	s.curBytes -= s.sizes[guid]
+	delete(s.sizes, guid)
+}
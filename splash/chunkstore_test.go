@@ -0,0 +1,92 @@
+package splash
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDiskChunkStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	store := newDiskChunkStore(t.TempDir(), 10)
+
+	store.Put("a", bytes.Repeat([]byte{1}, 5))
+	store.Put("b", bytes.Repeat([]byte{2}, 5))
+
+	// Touching "a" makes "b" the least recently used, so it should be the
+	// one evicted once a third chunk pushes curBytes over budget.
+	if _, ok := store.Get("a"); !ok {
+		t.Fatalf("expected a to be present before eviction")
+	}
+
+	store.Put("c", bytes.Repeat([]byte{3}, 5))
+
+	if _, ok := store.Get("b"); ok {
+		t.Errorf("expected b to be evicted, but it's still present")
+	}
+	if _, ok := store.Get("a"); !ok {
+		t.Errorf("expected a to survive eviction")
+	}
+	if _, ok := store.Get("c"); !ok {
+		t.Errorf("expected c to be present")
+	}
+	if store.curBytes > store.maxBytes {
+		t.Errorf("curBytes %d exceeds maxBytes %d after eviction", store.curBytes, store.maxBytes)
+	}
+}
+
+func TestDiskChunkStorePinSurvivesEviction(t *testing.T) {
+	store := newDiskChunkStore(t.TempDir(), 10)
+
+	store.Pin("a")
+	store.Put("a", bytes.Repeat([]byte{1}, 5))
+	store.Put("b", bytes.Repeat([]byte{2}, 5))
+
+	// "a" is the least recently used entry and would normally be evicted
+	// here, but it's pinned (still awaited by a consumer), so eviction must
+	// skip it and reclaim the next least recently used chunk, "b", instead.
+	store.Put("c", bytes.Repeat([]byte{3}, 5))
+
+	if _, ok := store.Get("a"); !ok {
+		t.Errorf("expected pinned chunk a to survive eviction")
+	}
+	if _, ok := store.Get("c"); !ok {
+		t.Errorf("expected c to be present")
+	}
+
+	store.Release("a")
+	if _, ok := store.Get("a"); ok {
+		t.Errorf("expected a to be gone after Release")
+	}
+}
+
+func TestDiskChunkStoreRelease(t *testing.T) {
+	store := newDiskChunkStore(t.TempDir(), 1024)
+
+	store.Put("a", []byte("hello"))
+	store.Release("a")
+
+	if _, ok := store.Get("a"); ok {
+		t.Errorf("expected a to be gone after Release")
+	}
+	if store.curBytes != 0 {
+		t.Errorf("curBytes = %d, want 0 after releasing the only chunk", store.curBytes)
+	}
+}
+
+func TestDiskChunkStorePutOverwritesSize(t *testing.T) {
+	store := newDiskChunkStore(t.TempDir(), 1024)
+
+	store.Put("a", bytes.Repeat([]byte{1}, 10))
+	store.Put("a", bytes.Repeat([]byte{2}, 3))
+
+	if store.curBytes != 3 {
+		t.Errorf("curBytes = %d, want 3 after re-Put with smaller data", store.curBytes)
+	}
+
+	data, ok := store.Get("a")
+	if !ok {
+		t.Fatalf("expected a to be present")
+	}
+	if !bytes.Equal(data, bytes.Repeat([]byte{2}, 3)) {
+		t.Errorf("Get returned stale data %v", data)
+	}
+}
@@ -0,0 +1,230 @@
+package splash
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// chunkWorker pulls GUIDs off jobs, downloads and decompresses each chunk
+// and publishes the result into pool. Chunks already present in the store
+// (e.g. reused from a previous run) are published without re-fetching, and
+// chunks in rangeWindows are fetched with an HTTP range request instead of
+// in full. It keeps going until jobs is closed; once ctx is canceled, every
+// remaining job is failed with ctx's error instead of fetched, so nothing
+// waiting on those chunks in pool is left blocked.
+func (d *Downloader) chunkWorker(ctx context.Context, jobs <-chan string, manifestChunks map[string]Chunk, rangeWindows map[string]chunkWindow, pool *chunkPool, tracker *progressTracker, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for guid := range jobs {
+		if err := ctx.Err(); err != nil {
+			pool.fail(guid, err)
+			continue
+		}
+
+		chunk := manifestChunks[guid]
+
+		if _, ok := pool.store.Get(guid); ok {
+			pool.publishExisting(guid)
+			d.recordChunkProgress(tracker, chunk)
+			continue
+		}
+
+		if w, ok := rangeWindows[guid]; ok {
+			if storedAs, err := d.probeStoredAsWithRetry(ctx, chunk); err == nil && storedAs == 0 {
+				chunkData, err := d.downloadChunkRangeWithRetry(ctx, chunk, w.offset, w.size)
+				if err != nil {
+					pool.fail(guid, err)
+					continue
+				}
+				pool.putRange(guid, chunkData)
+				d.recordChunkProgress(tracker, chunk)
+				continue
+			}
+			// Compressed, or the probe itself failed - DownloadRange can't
+			// be trusted here, so fall through to a full fetch+decompress.
+		}
+
+		chunkData, err := d.downloadChunkWithRetry(ctx, chunk)
+		if err != nil {
+			pool.fail(guid, err)
+			continue
+		}
+
+		pool.put(guid, chunkData)
+		d.recordChunkProgress(tracker, chunk)
+	}
+}
+
+// recordChunkProgress records one more completed chunk against tracker and
+// reports the updated run-wide totals via d.Progress.
+func (d *Downloader) recordChunkProgress(tracker *progressTracker, chunk Chunk) {
+	chunksDone, bytesDone := tracker.recordChunk(uint64(chunk.FileSize))
+	d.reportProgress(ProgressEvent{
+		BytesDone:   bytesDone,
+		BytesTotal:  tracker.bytesTotal,
+		ChunksDone:  chunksDone,
+		ChunksTotal: tracker.chunksTotal,
+		Phase:       PhaseDownload,
+	})
+}
+
+// sleepWithContext waits out delay, returning early with ctx.Err() if ctx
+// is canceled first.
+func sleepWithContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// probeStoredAsWithRetry fetches a chunk's header to learn its StoredAs
+// storage mode, retrying with the same backoff and per-attempt random URL
+// choice as the other fetch helpers. Callers use this before a DownloadRange fetch:
+// DownloadRange never decompresses, so a window sliced out of a
+// zlib-compressed chunk would silently come back as garbage instead of the
+// plaintext bytes the caller expects.
+func (d *Downloader) probeStoredAsWithRetry(ctx context.Context, chunk Chunk) (int, error) {
+	const maxAttempts = 6
+
+	var lastErr error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if attempt > 0 {
+			if err := sleepWithContext(ctx, backoffDelay(attempt)); err != nil {
+				return 0, err
+			}
+		}
+
+		url := d.pickDownloadURL()
+		storedAs, err := chunk.storedAs(url, d.httpClient())
+		if err != nil {
+			lastErr = fmt.Errorf("probe header from %s: %w", url, err)
+			continue
+		}
+
+		return storedAs, nil
+	}
+
+	return 0, fmt.Errorf("giving up after %d attempts: %w", maxAttempts, lastErr)
+}
+
+// downloadChunkRangeWithRetry fetches just a chunk's referenced window via
+// DownloadRange, retrying with the same backoff and per-attempt random URL
+// choice as downloadChunkWithRetry. Callers must confirm the chunk is uncompressed
+// (StoredAs == 0) via probeStoredAsWithRetry first; DownloadRange only
+// slices out a byte range and never decompresses, so using it against a
+// compressed chunk would corrupt the result.
+func (d *Downloader) downloadChunkRangeWithRetry(ctx context.Context, chunk Chunk, offset, size uint32) ([]byte, error) {
+	const maxAttempts = 6
+
+	var lastErr error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if attempt > 0 {
+			if err := sleepWithContext(ctx, backoffDelay(attempt)); err != nil {
+				return nil, err
+			}
+		}
+
+		url := d.pickDownloadURL()
+		chunkData, err := chunk.DownloadRange(url, d.httpClient(), offset, size)
+		if err != nil {
+			lastErr = fmt.Errorf("range download from %s: %w", url, err)
+			continue
+		}
+
+		return chunkData, nil
+	}
+
+	return nil, fmt.Errorf("giving up after %d attempts: %w", maxAttempts, lastErr)
+}
+
+// downloadChunkWithRetry fetches and decompresses a single chunk, retrying
+// with exponential backoff and jitter on failure. Each attempt picks a
+// random entry of DownloadURLs, so a retry usually goes to a different edge
+// node than the one that failed; successive attempts can still pick the
+// same URL, as the choice does not cycle through the list.
+func (d *Downloader) downloadChunkWithRetry(ctx context.Context, chunk Chunk) ([]byte, error) {
+	const maxAttempts = 6
+
+	var lastErr error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if attempt > 0 {
+			if err := sleepWithContext(ctx, backoffDelay(attempt)); err != nil {
+				return nil, err
+			}
+		}
+
+		url := d.pickDownloadURL()
+		chunkData, err := chunk.Download(url, d.httpClient())
+		if err != nil {
+			lastErr = fmt.Errorf("download from %s: %w", url, err)
+			continue
+		}
+
+		chunkReader := bytes.NewReader(chunkData)
+		chunkHeader, err := readChunkHeader(chunkReader)
+		if err != nil {
+			lastErr = fmt.Errorf("read header: %w", err)
+			continue
+		}
+
+		switch chunkHeader.StoredAs {
+		case 0: // uncompressed, strip the header
+			chunkData = chunkData[chunkHeaderSize:]
+		case 1: // zlib compressed
+			zlibReader, err := zlib.NewReader(chunkReader)
+			if err != nil {
+				lastErr = fmt.Errorf("create decompressor: %w", err)
+				continue
+			}
+
+			chunkData, err = ioutil.ReadAll(zlibReader)
+			if err != nil {
+				lastErr = fmt.Errorf("decompress: %w", err)
+				continue
+			}
+		default:
+			return nil, fmt.Errorf("unknown chunk storage mode %d", chunkHeader.StoredAs)
+		}
+
+		if expected := chunk.Sha; len(expected) > 0 {
+			sum := sha1.Sum(chunkData)
+			if !bytes.Equal(sum[:], expected) {
+				lastErr = fmt.Errorf("sha1 mismatch: got %s want %s", hex.EncodeToString(sum[:]), hex.EncodeToString(expected))
+				continue
+			}
+		}
+
+		return chunkData, nil
+	}
+
+	return nil, fmt.Errorf("giving up after %d attempts: %w", maxAttempts, lastErr)
+}
+
+// backoffDelay returns an exponential backoff duration for the given retry
+// attempt (1-indexed), with up to 50% jitter to avoid a thundering herd of
+// workers retrying a flaky edge node in lockstep.
+func backoffDelay(attempt int) time.Duration {
+	base := 250 * time.Millisecond
+	maxDelay := 10 * time.Second
+
+	delay := base << uint(attempt-1)
+	if delay > maxDelay || delay <= 0 {
+		delay = maxDelay
+	}
+
+	jitter := time.Duration(rand.Int63n(int64(delay) / 2))
+	return delay/2 + jitter
+}
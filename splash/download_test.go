@@ -0,0 +1,30 @@
+package splash
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		attempt int
+		min     time.Duration
+		max     time.Duration
+	}{
+		{"first retry", 1, 125 * time.Millisecond, 250 * time.Millisecond},
+		{"second retry", 2, 250 * time.Millisecond, 500 * time.Millisecond},
+		{"large attempt caps at maxDelay", 30, 5 * time.Second, 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 20; i++ { // jitter is random, so sample a few draws
+				d := backoffDelay(tt.attempt)
+				if d < tt.min || d > tt.max {
+					t.Fatalf("backoffDelay(%d) = %v, want between %v and %v", tt.attempt, d, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
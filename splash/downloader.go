@@ -0,0 +1,503 @@
+// Package splash is a programmatic client for fetching and assembling
+// Fortnite builds from Epic's chunked manifest/CDN distribution format. It
+// powers the splash CLI (see cmd/splash) but is also meant to be embedded
+// directly in bots, launchers, and test harnesses.
+package splash
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// defaultDownloadURL is used when a Downloader doesn't set DownloadURLs.
+const defaultDownloadURL = "http://epicgames-download1.akamaized.net"
+
+// maxInFlightChunks is the entry budget of the default in-memory
+// ChunkStore. Its eviction only reclaims unpinned chunks, and chunkPool pins
+// every chunk of a run until the assembly loop has consumed it, so during
+// DownloadFiles this does not bound how many decompressed chunks stay
+// resident: chunks that workers finish ahead of the file-assembly goroutine
+// all stay in memory until they are consumed.
+const maxInFlightChunks = 64
+
+// rangeFetchThreshold is the fraction of a chunk's full size below which a
+// single-use referenced window is cheaper to fetch with an HTTP range
+// request than to download and decompress in full.
+const rangeFetchThreshold = 0.25
+
+// Downloader fetches and assembles files from an Epic manifest. Its zero
+// value works: an unset HTTPClient, DownloadURLs, Logger or Workers falls
+// back to http.DefaultClient, Epic's CDN, no logging and a single worker.
+// NewDownloader instead sets the CLI's defaults (a stderr Logger and 4
+// Workers); set the remaining fields after either.
+type Downloader struct {
+	Platform     string
+	InstallPath  string
+	CachePath    string
+	DownloadURLs []string
+	HTTPClient   *http.Client
+	Logger       *log.Logger
+	Progress     func(ProgressEvent)
+
+	// Workers is how many chunks are downloaded concurrently.
+	Workers int
+	// ChunkCacheSize is the byte budget for an on-disk LRU chunk cache
+	// under CachePath/chunks. Zero keeps reused chunks in memory instead.
+	// Ignored if ChunkStore is set.
+	ChunkCacheSize int64
+	// ChunkStore, if set, overrides where in-flight chunks are held instead
+	// of the ChunkCacheSize-driven choice between memChunkStore and
+	// diskChunkStore. Mainly useful for substituting a mock in tests.
+	ChunkStore ChunkStore
+	// Repair, when set, makes DownloadFiles verify existing files
+	// chunk-by-chunk and only re-fetch the chunks that are corrupt,
+	// instead of dropping a whole file on a top-level hash mismatch.
+	Repair bool
+
+	// chunkSourceIndex caches the GUID->on-disk-window index RepairFile
+	// builds from the manifest, so repairing many files in one run only
+	// pays for the scan once. See chunkSources.
+	chunkSourceIndex map[string][]chunkSource
+}
+
+// NewDownloader returns a Downloader with working defaults for HTTPClient,
+// Logger, DownloadURLs and Workers. Callers typically still set Platform,
+// InstallPath and CachePath.
+func NewDownloader() *Downloader {
+	return &Downloader{
+		Platform:     "Windows",
+		DownloadURLs: []string{defaultDownloadURL},
+		HTTPClient:   &http.Client{},
+		Logger:       log.New(os.Stderr, "", log.LstdFlags),
+		Workers:      4,
+	}
+}
+
+// FileError pairs a manifest file with the error VerifyFiles encountered
+// verifying it.
+type FileError struct {
+	FileName string
+	Err      error
+}
+
+func (e FileError) Error() string {
+	return fmt.Sprintf("%s: %v", e.FileName, e.Err)
+}
+
+func (d *Downloader) logger() *log.Logger {
+	if d.Logger != nil {
+		return d.Logger
+	}
+	return log.New(ioutil.Discard, "", 0)
+}
+
+func (d *Downloader) downloadURLs() []string {
+	if len(d.DownloadURLs) > 0 {
+		return d.DownloadURLs
+	}
+	return []string{defaultDownloadURL}
+}
+
+func (d *Downloader) pickDownloadURL() string {
+	urls := d.downloadURLs()
+	return urls[rand.Intn(len(urls))]
+}
+
+func (d *Downloader) httpClient() *http.Client {
+	if d.HTTPClient != nil {
+		return d.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// LoadCatalog loads the Fortnite catalog, preferring a cached copy under
+// CachePath before fetching the latest one from Epic's MCP service.
+func (d *Downloader) LoadCatalog() (*Catalog, error) {
+	os.MkdirAll(d.CachePath, os.ModePerm)
+
+	catalogCachePath := filepath.Join(d.CachePath, "catalog.json")
+	if _, err := os.Stat(catalogCachePath); err == nil {
+		d.logger().Println("Loading catalog from cache...")
+
+		catalog, err := readCatalogFile(catalogCachePath)
+		if err != nil {
+			return nil, fmt.Errorf("load cached catalog: %w", err)
+		}
+		return catalog, nil
+	}
+
+	d.logger().Println("Fetching latest catalog...")
+
+	catalogBytes, err := fetchCatalog(d.Platform, "fn", "4fe75bbc5a674f4f9b356b5c90567da5", "Fortnite", "Live")
+	if err != nil {
+		return nil, fmt.Errorf("fetch catalog: %w", err)
+	}
+
+	catalog, err := parseCatalog(catalogBytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse catalog: %w", err)
+	}
+
+	if len(catalog.Elements) != 1 || len(catalog.Elements[0].Manifests) < 1 {
+		return nil, fmt.Errorf("unsupported catalog")
+	}
+
+	ioutil.WriteFile(catalogCachePath, catalogBytes, 0644)
+
+	return catalog, nil
+}
+
+// LoadManifest loads the manifest for catalog, or a specific manifestID if
+// one is given, preferring a cached copy under CachePath.
+func (d *Downloader) LoadManifest(catalog *Catalog, manifestID string) (*Manifest, error) {
+	manifestCachePath := filepath.Join(d.CachePath, "manifest.json")
+
+	if manifestID != "" {
+		d.logger().Printf("Fetching manifest %s...\n", manifestID)
+
+		manifest, _, err := fetchManifest(fmt.Sprintf("%s/Builds/Fortnite/CloudDir/%s.manifest", defaultDownloadURL, manifestID))
+		if err != nil {
+			return nil, fmt.Errorf("fetch manifest: %w", err)
+		}
+		return manifest, nil
+	}
+
+	if _, err := os.Stat(manifestCachePath); err == nil {
+		d.logger().Println("Loading manifest from cache...")
+
+		manifest, err := readManifestFile(manifestCachePath)
+		if err != nil {
+			return nil, fmt.Errorf("load cached manifest: %w", err)
+		}
+		return manifest, nil
+	}
+
+	d.logger().Println("Fetching latest manifest...")
+
+	manifest, manifestBytes, err := fetchManifest(catalog.GetManifestURL())
+	if err != nil {
+		return nil, fmt.Errorf("fetch manifest: %w", err)
+	}
+	ioutil.WriteFile(manifestCachePath, manifestBytes, 0644)
+
+	return manifest, nil
+}
+
+// filteredManifestFiles returns the files in manifest matching filter (by
+// FileName), or every file if filter is empty.
+func filteredManifestFiles(manifest *Manifest, filter []string) map[string]ManifestFile {
+	manifestFiles := make(map[string]ManifestFile, len(manifest.FileManifestList))
+	for _, file := range manifest.FileManifestList {
+		manifestFiles[file.FileName] = file
+	}
+
+	if len(filter) == 0 {
+		return manifestFiles
+	}
+
+	filtered := make(map[string]ManifestFile, len(filter))
+	for _, fileName := range filter {
+		if f, ok := manifestFiles[fileName]; ok {
+			filtered[fileName] = f
+		}
+	}
+	return filtered
+}
+
+// DownloadFiles downloads and assembles the files in manifest matching
+// filter (or every file, if filter is empty) into InstallPath. It fans
+// chunk downloads out across Workers goroutines and is safe to cancel via
+// ctx, which aborts as soon as in-flight work notices it.
+func (d *Downloader) DownloadFiles(ctx context.Context, manifest *Manifest, filter []string) error {
+	manifestFiles := filteredManifestFiles(manifest, filter)
+	d.logger().Printf("Found %d files to download.\n", len(manifestFiles))
+
+	filesToFetch, repairNeeded, manifestChunks, chunkReverseMap, chunkWindows := d.planDownload(manifestFiles, manifest)
+
+	d.logger().Printf("Downloading %d files from %d chunks using %d workers...\n", len(filesToFetch), len(manifestChunks), d.workerCount())
+
+	rangeWindows := rangeFetchCandidates(manifestChunks, chunkReverseMap, chunkWindows)
+
+	var bytesTotal uint64
+	for _, c := range manifestChunks {
+		bytesTotal += uint64(c.FileSize)
+	}
+	tracker := newProgressTracker(len(manifestChunks), bytesTotal)
+
+	pool, wg := d.startWorkerPool(ctx, manifestChunks, rangeWindows, tracker)
+	defer wg.Wait()
+
+	return d.assembleFiles(ctx, filesToFetch, repairNeeded, chunkReverseMap, pool)
+}
+
+func (d *Downloader) workerCount() int {
+	if d.Workers > 0 {
+		return d.Workers
+	}
+	return 1
+}
+
+// planDownload drops files that already match their expected hash on disk
+// (or, in Repair mode, figures out exactly which of their chunks are
+// corrupt) and collects the set of chunks still needed to build the rest.
+func (d *Downloader) planDownload(manifestFiles map[string]ManifestFile, manifest *Manifest) (
+	filesToFetch []ManifestFile,
+	repairNeeded map[string]map[string]bool,
+	manifestChunks map[string]Chunk,
+	chunkReverseMap map[string]int,
+	chunkWindows map[string]chunkWindow,
+) {
+	filesToFetch = make([]ManifestFile, 0, len(manifestFiles))
+	repairNeeded = make(map[string]map[string]bool)
+	chunkReverseMap = make(map[string]int)
+	chunkWindows = make(map[string]chunkWindow)
+	manifestChunks = make(map[string]Chunk)
+
+	for _, file := range manifestFiles {
+		var neededGUIDs map[string]bool
+
+		if d.Repair {
+			needed, rerr := d.RepairFile(file, manifest)
+			if rerr != nil {
+				d.logger().Printf("Failed to repair-check %s: %v\n", file.FileName, rerr)
+			} else if len(needed) == 0 {
+				d.logger().Printf("File %s passed repair check!\n", file.FileName)
+				continue
+			} else {
+				d.logger().Printf("File %s needs %d/%d chunks repaired.\n", file.FileName, len(needed), len(file.FileChunkParts))
+				neededGUIDs = make(map[string]bool, len(needed))
+				for _, guid := range needed {
+					neededGUIDs[guid] = true
+				}
+				repairNeeded[file.FileName] = neededGUIDs
+			}
+		} else if d.fileMatchesOnDisk(file) {
+			d.logger().Printf("File %s found on disk!\n", file.FileName)
+			continue
+		}
+
+		filesToFetch = append(filesToFetch, file)
+		for _, c := range file.FileChunkParts {
+			if neededGUIDs != nil && !neededGUIDs[c.GUID] {
+				continue // already correct on disk, nothing to fetch
+			}
+
+			chunkReverseMap[c.GUID]++
+			chunkWindows[c.GUID] = chunkWindow{offset: readPackedUint32(c.Offset), size: readPackedUint32(c.Size)}
+
+			if _, ok := manifestChunks[c.GUID]; !ok { // don't add duplicates
+				manifestChunks[c.GUID] = NewChunk(c.GUID, manifest.ChunkHashList[c.GUID], manifest.ChunkShaList[c.GUID], manifest.DataGroupList[c.GUID], manifest.ChunkFilesizeList[c.GUID])
+			}
+		}
+	}
+
+	return filesToFetch, repairNeeded, manifestChunks, chunkReverseMap, chunkWindows
+}
+
+func (d *Downloader) fileMatchesOnDisk(file ManifestFile) bool {
+	filePath := filepath.Join(d.InstallPath, file.FileName)
+
+	diskFile, err := os.Open(filePath)
+	if err != nil {
+		return false
+	}
+	defer diskFile.Close()
+
+	hasher := sha1.New()
+	if _, err := io.Copy(hasher, diskFile); err != nil {
+		return false
+	}
+
+	return bytes.Equal(hasher.Sum(nil), readPackedData(file.FileHash))
+}
+
+// rangeFetchCandidates picks out chunks that are referenced exactly once
+// and whose window is small relative to the chunk's full size, which are
+// cheaper to fetch with an HTTP range request than in full.
+func rangeFetchCandidates(manifestChunks map[string]Chunk, chunkReverseMap map[string]int, chunkWindows map[string]chunkWindow) map[string]chunkWindow {
+	rangeWindows := make(map[string]chunkWindow)
+	for guid, count := range chunkReverseMap {
+		if count != 1 {
+			continue
+		}
+		w := chunkWindows[guid]
+		if chunk := manifestChunks[guid]; chunk.FileSize > 0 && float64(w.size) < rangeFetchThreshold*float64(chunk.FileSize) {
+			rangeWindows[guid] = w
+		}
+	}
+	return rangeWindows
+}
+
+func (d *Downloader) startWorkerPool(ctx context.Context, manifestChunks map[string]Chunk, rangeWindows map[string]chunkWindow, tracker *progressTracker) (*chunkPool, *sync.WaitGroup) {
+	neededGUIDs := make([]string, 0, len(manifestChunks))
+	for guid := range manifestChunks {
+		neededGUIDs = append(neededGUIDs, guid)
+	}
+
+	store := d.ChunkStore
+	if store == nil {
+		if d.ChunkCacheSize > 0 {
+			store = newDiskChunkStore(filepath.Join(d.CachePath, "chunks"), d.ChunkCacheSize)
+		} else {
+			store = newMemChunkStore(maxInFlightChunks)
+		}
+	}
+
+	pool := newChunkPool(neededGUIDs, store)
+
+	jobs := make(chan string, len(neededGUIDs))
+	for _, guid := range neededGUIDs {
+		jobs <- guid
+	}
+	close(jobs)
+
+	var wg sync.WaitGroup
+	for i := 0; i < d.workerCount(); i++ {
+		wg.Add(1)
+		go d.chunkWorker(ctx, jobs, manifestChunks, rangeWindows, pool, tracker, &wg)
+	}
+
+	return pool, &wg
+}
+
+// assembleFiles waits for each file's chunks to become available and writes
+// them out sequentially, so file order and layout on disk stay
+// deterministic even though chunks can arrive out of order.
+func (d *Downloader) assembleFiles(ctx context.Context, filesToFetch []ManifestFile, repairNeeded map[string]map[string]bool, chunkReverseMap map[string]int, pool *chunkPool) error {
+	for _, file := range filesToFetch {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		filePath := filepath.Join(d.InstallPath, file.FileName)
+		neededGUIDs := repairNeeded[file.FileName]
+
+		var outFile *os.File
+		var err error
+		if neededGUIDs != nil { // repairing an existing file in place, or performing its initial download
+			os.MkdirAll(filepath.Dir(filePath), os.ModePerm)
+			outFile, err = os.OpenFile(filePath, os.O_RDWR|os.O_CREATE, 0644)
+		} else {
+			d.logger().Printf("Downloading %s from %d chunks...\n", file.FileName, len(file.FileChunkParts))
+			os.MkdirAll(filepath.Dir(filePath), os.ModePerm)
+			outFile, err = os.Create(filePath)
+		}
+		if err != nil {
+			d.logger().Printf("Failed to open %s: %v\n", filePath, err)
+			continue
+		}
+
+		d.writeFileChunks(ctx, file, outFile, neededGUIDs, chunkReverseMap, pool)
+		outFile.Close()
+	}
+
+	return ctx.Err()
+}
+
+func (d *Downloader) writeFileChunks(ctx context.Context, file ManifestFile, outFile *os.File, neededGUIDs map[string]bool, chunkReverseMap map[string]int, pool *chunkPool) {
+	var fileOffset int64
+	for _, chunkPart := range file.FileChunkParts {
+		chunkDataOffset := readPackedUint32(chunkPart.Offset)
+		chunkDataSize := int64(readPackedUint32(chunkPart.Size))
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		if neededGUIDs != nil && !neededGUIDs[chunkPart.GUID] {
+			fileOffset += chunkDataSize
+			continue
+		}
+
+		chunkData, err := pool.wait(chunkPart.GUID)
+		if err != nil {
+			d.logger().Printf("Failed to download chunk %s for file %s: %v\n", chunkPart.GUID, file.FileName, err)
+			fileOffset += chunkDataSize
+			continue
+		}
+
+		// Range-fetched chunks already contain exactly this window, so
+		// there's no offset to seek past like there is for a fully
+		// downloaded chunk.
+		chunkReader := bytes.NewReader(chunkData)
+		if !pool.wasRangeFetched(chunkPart.GUID) {
+			chunkReader.Seek(int64(chunkDataOffset), io.SeekCurrent)
+		}
+		outFile.Seek(fileOffset, io.SeekStart)
+		if _, err := io.CopyN(outFile, chunkReader, chunkDataSize); err != nil {
+			d.logger().Printf("Failed to write chunk %s to file %s: %v\n", chunkPart.GUID, file.FileName, err)
+		}
+		fileOffset += chunkDataSize
+
+		// Chunk was used once, whether or not the write above succeeded -
+		// either way pool.wait already handed us the data, so this
+		// reference is done with it and its pool slot can be released.
+		chunkReverseMap[chunkPart.GUID]--
+
+		// Check if we still need to keep the chunk resident
+		if chunkReverseMap[chunkPart.GUID] < 1 {
+			pool.evict(chunkPart.GUID)
+		}
+	}
+}
+
+// VerifyFiles re-hashes every file in manifest matching filter (or every
+// file, if filter is empty) against its expected checksum and reports any
+// that don't match.
+func (d *Downloader) VerifyFiles(ctx context.Context, manifest *Manifest, filter []string) ([]FileError, error) {
+	manifestFiles := filteredManifestFiles(manifest, filter)
+	filesTotal := len(manifestFiles)
+
+	var fileErrors []FileError
+	var filesDone int
+	for _, file := range manifestFiles {
+		if err := ctx.Err(); err != nil {
+			return fileErrors, err
+		}
+
+		filePath := filepath.Join(d.InstallPath, file.FileName)
+
+		f, err := os.Open(filePath)
+		if err != nil {
+			fileErrors = append(fileErrors, FileError{FileName: file.FileName, Err: err})
+			filesDone++
+			d.reportProgress(ProgressEvent{FileName: file.FileName, ChunksDone: filesDone, ChunksTotal: filesTotal, Phase: PhaseVerify})
+			continue
+		}
+
+		hasher := sha1.New()
+		_, err = io.Copy(hasher, f)
+		f.Close()
+		if err != nil {
+			fileErrors = append(fileErrors, FileError{FileName: file.FileName, Err: err})
+			filesDone++
+			d.reportProgress(ProgressEvent{FileName: file.FileName, ChunksDone: filesDone, ChunksTotal: filesTotal, Phase: PhaseVerify})
+			continue
+		}
+
+		expectedHash := readPackedData(file.FileHash)
+		actualHash := hasher.Sum(nil)
+		if !bytes.Equal(actualHash, expectedHash) {
+			fileErrors = append(fileErrors, FileError{
+				FileName: file.FileName,
+				Err:      fmt.Errorf("corrupt: got hash %s but want %s", hex.EncodeToString(actualHash), hex.EncodeToString(expectedHash)),
+			})
+		}
+
+		filesDone++
+		d.reportProgress(ProgressEvent{FileName: file.FileName, ChunksDone: filesDone, ChunksTotal: filesTotal, Phase: PhaseVerify})
+	}
+
+	return fileErrors, nil
+}
@@ -0,0 +1,42 @@
+package splash
+
+import "testing"
+
+func TestRangeFetchCandidates(t *testing.T) {
+	manifestChunks := map[string]Chunk{
+		"single-small-window": NewChunk("single-small-window", nil, nil, 0, 1000),
+		"single-large-window": NewChunk("single-large-window", nil, nil, 0, 1000),
+		"reused-small-window": NewChunk("reused-small-window", nil, nil, 0, 1000),
+		"zero-size":           NewChunk("zero-size", nil, nil, 0, 0),
+	}
+	chunkReverseMap := map[string]int{
+		"single-small-window": 1,
+		"single-large-window": 1,
+		"reused-small-window": 2,
+		"zero-size":           1,
+	}
+	chunkWindows := map[string]chunkWindow{
+		"single-small-window": {offset: 0, size: 100}, // 10% of 1000, below threshold
+		"single-large-window": {offset: 0, size: 500}, // 50% of 1000, at/above threshold
+		"reused-small-window": {offset: 0, size: 100}, // small, but referenced twice
+		"zero-size":           {offset: 0, size: 0},
+	}
+
+	got := rangeFetchCandidates(manifestChunks, chunkReverseMap, chunkWindows)
+
+	if _, ok := got["single-small-window"]; !ok {
+		t.Errorf("expected single-small-window to be a range-fetch candidate")
+	}
+	if _, ok := got["single-large-window"]; ok {
+		t.Errorf("single-large-window's window isn't small enough to be a candidate")
+	}
+	if _, ok := got["reused-small-window"]; ok {
+		t.Errorf("reused-small-window is referenced twice, shouldn't be a candidate")
+	}
+	if _, ok := got["zero-size"]; ok {
+		t.Errorf("zero-size chunk shouldn't be a candidate")
+	}
+	if len(got) != 1 {
+		t.Errorf("rangeFetchCandidates returned %d candidates, want 1", len(got))
+	}
+}
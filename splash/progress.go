@@ -0,0 +1,176 @@
+package splash
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+)
+
+// Phase identifies which stage of a run a ProgressEvent was emitted from.
+type Phase int
+
+const (
+	PhaseDownload Phase = iota
+	PhaseVerify
+)
+
+// ProgressEvent is reported to Downloader.Progress as files are downloaded
+// and verified. BytesDone/BytesTotal and ChunksDone/ChunksTotal are
+// cumulative counts for the whole run, not just the named file. PhaseDownload
+// events leave FileName empty. In PhaseVerify events ChunksDone/ChunksTotal
+// count files verified rather than chunks, and BytesDone/BytesTotal are 0.
+type ProgressEvent struct {
+	FileName    string
+	BytesDone   uint64
+	BytesTotal  uint64
+	ChunksDone  int
+	ChunksTotal int
+	Phase       Phase
+}
+
+func (d *Downloader) reportProgress(ev ProgressEvent) {
+	if d.Progress != nil {
+		d.Progress(ev)
+	}
+}
+
+// progressTracker accumulates download progress across concurrent chunk
+// workers so each ProgressEvent carries a consistent run-wide total.
+type progressTracker struct {
+	chunksTotal int
+	bytesTotal  uint64
+
+	mu         sync.Mutex
+	chunksDone int
+	bytesDone  uint64
+}
+
+func newProgressTracker(chunksTotal int, bytesTotal uint64) *progressTracker {
+	return &progressTracker{chunksTotal: chunksTotal, bytesTotal: bytesTotal}
+}
+
+// recordChunk registers one more completed chunk of size bytes and returns
+// the updated totals.
+func (t *progressTracker) recordChunk(size uint64) (chunksDone int, bytesDone uint64) {
+	t.mu.Lock()
+	t.chunksDone++
+	t.bytesDone += size
+	chunksDone, bytesDone = t.chunksDone, t.bytesDone
+	t.mu.Unlock()
+	return
+}
+
+// ewmaWindow is the time constant for the throughput estimate TerminalProgress
+// reports: a sample from 10s ago has decayed to ~37% of its original weight.
+const ewmaWindow = 10 * time.Second
+
+// ewmaRate is a time-decayed throughput estimate in bytes/sec.
+type ewmaRate struct {
+	mu        sync.Mutex
+	lastTime  time.Time
+	lastBytes uint64
+	rate      float64
+}
+
+func (e *ewmaRate) update(bytesDone uint64, now time.Time) float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.lastTime.IsZero() {
+		e.lastTime, e.lastBytes = now, bytesDone
+		return e.rate
+	}
+
+	dt := now.Sub(e.lastTime).Seconds()
+	if dt <= 0 {
+		return e.rate
+	}
+
+	instant := float64(bytesDone-e.lastBytes) / dt
+	alpha := 1 - math.Exp(-dt/ewmaWindow.Seconds())
+	e.rate = alpha*instant + (1-alpha)*e.rate
+	e.lastTime, e.lastBytes = now, bytesDone
+
+	return e.rate
+}
+
+// TerminalProgress returns a Downloader.Progress callback that renders a
+// single, continuously-updating line to w with percentage complete,
+// throughput (EWMA'd over the last 10s) and ETA.
+func TerminalProgress(w io.Writer) func(ProgressEvent) {
+	rate := &ewmaRate{}
+
+	return func(ev ProgressEvent) {
+		r := rate.update(ev.BytesDone, time.Now())
+
+		var pct float64
+		if ev.BytesTotal > 0 {
+			pct = float64(ev.BytesDone) / float64(ev.BytesTotal) * 100
+		}
+
+		eta := "--:--"
+		if r > 0 && ev.BytesTotal > ev.BytesDone {
+			eta = formatETA(time.Duration(float64(ev.BytesTotal-ev.BytesDone) / r * float64(time.Second)))
+		}
+
+		fmt.Fprintf(w, "\r%s %5.1f%%  %s/s  chunks %d/%d  eta %s   ",
+			phaseLabel(ev.Phase), pct, humanBytes(uint64(r)), ev.ChunksDone, ev.ChunksTotal, eta)
+	}
+}
+
+// JSONProgress returns a Downloader.Progress callback that writes each
+// event to w as a line of newline-delimited JSON, for GUIs and CI to
+// consume.
+func JSONProgress(w io.Writer) func(ProgressEvent) {
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+
+	return func(ev ProgressEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		enc.Encode(ev)
+	}
+}
+
+func phaseLabel(p Phase) string {
+	if p == PhaseVerify {
+		return "Verifying"
+	}
+	return "Downloading"
+}
+
+func humanBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func formatETA(d time.Duration) string {
+	if d <= 0 {
+		return "--:--"
+	}
+
+	d = d.Round(time.Second)
+	h := d / time.Hour
+	d -= h * time.Hour
+	m := d / time.Minute
+	d -= m * time.Minute
+	s := d / time.Second
+
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%02d:%02d", m, s)
+}
@@ -0,0 +1,41 @@
+package splash
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEwmaRateFirstSampleHasNoRate(t *testing.T) {
+	e := &ewmaRate{}
+	if r := e.update(0, time.Unix(0, 0)); r != 0 {
+		t.Errorf("first update() = %v, want 0 (no prior sample to diff against)", r)
+	}
+}
+
+func TestEwmaRateConvergesToSteadyThroughput(t *testing.T) {
+	e := &ewmaRate{}
+	start := time.Unix(0, 0)
+	e.update(0, start)
+
+	const bytesPerSec = 1000.0
+	var r float64
+	for i := 1; i <= 60; i++ {
+		r = e.update(uint64(bytesPerSec*float64(i)), start.Add(time.Duration(i)*time.Second))
+	}
+
+	if math.Abs(r-bytesPerSec) > bytesPerSec*0.05 {
+		t.Errorf("rate after steady 60s run = %v, want within 5%% of %v", r, bytesPerSec)
+	}
+}
+
+func TestEwmaRateIgnoresNonPositiveElapsed(t *testing.T) {
+	e := &ewmaRate{}
+	now := time.Unix(0, 0)
+	e.update(100, now)
+
+	r := e.update(200, now) // same timestamp, dt == 0
+	if r != 0 {
+		t.Errorf("update() with dt=0 = %v, want unchanged rate 0", r)
+	}
+}
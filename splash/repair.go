@@ -0,0 +1,151 @@
+package splash
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"os"
+	"path/filepath"
+)
+
+// chunkSource locates one on-disk window that contributes to a chunk's
+// original decompressed body: which file it's embedded in, where in that
+// file it sits, and the window's offset and size within the chunk itself.
+// A chunk can be split across several FileChunkParts - reused across
+// files, or only partially referenced by any one of them - so fully
+// verifying it means reassembling it from every window that references it,
+// not just the one in the file currently being checked.
+type chunkSource struct {
+	fileName    string
+	fileOffset  int64
+	chunkOffset int64
+	size        int64
+}
+
+// RepairFile checks an existing file on disk against the manifest
+// chunk-by-chunk and returns the GUIDs of the chunks that are missing or
+// whose bytes don't match manifest.ChunkShaList. If the file doesn't exist
+// at all, every chunk it's made of is returned. A caller can feed the
+// result straight into the chunk downloader and then patch just those
+// windows back into the file, turning a corrupt or partially-downloaded
+// file into a handful of chunk fetches instead of a full re-download.
+func (d *Downloader) RepairFile(file ManifestFile, manifest *Manifest) ([]string, error) {
+	filePath := filepath.Join(d.InstallPath, file.FileName)
+
+	if _, err := os.Stat(filePath); err != nil {
+		return allChunkGUIDs(file), nil
+	}
+
+	sources := d.chunkSources(manifest)
+
+	seen := make(map[string]bool)
+	var needed []string
+	for _, part := range file.FileChunkParts {
+		if seen[part.GUID] {
+			continue
+		}
+		seen[part.GUID] = true
+
+		if !d.chunkMatchesOnDisk(part.GUID, manifest, sources) {
+			needed = append(needed, part.GUID)
+		}
+	}
+
+	return needed, nil
+}
+
+// chunkSources indexes every FileChunkPart in manifest by the GUID it
+// references, so a chunk's full body can be reassembled from wherever its
+// windows live on disk. It's shared by every file RepairFile checks in a
+// run, so it's built once and cached rather than rescanned per file.
+func (d *Downloader) chunkSources(manifest *Manifest) map[string][]chunkSource {
+	if d.chunkSourceIndex != nil {
+		return d.chunkSourceIndex
+	}
+
+	index := make(map[string][]chunkSource)
+	for _, mf := range manifest.FileManifestList {
+		var fileOffset int64
+		for _, part := range mf.FileChunkParts {
+			size := int64(readPackedUint32(part.Size))
+			index[part.GUID] = append(index[part.GUID], chunkSource{
+				fileName:    mf.FileName,
+				fileOffset:  fileOffset,
+				chunkOffset: int64(readPackedUint32(part.Offset)),
+				size:        size,
+			})
+			fileOffset += size
+		}
+	}
+
+	d.chunkSourceIndex = index
+	return index
+}
+
+// chunkMatchesOnDisk reassembles guid's full decompressed body out of
+// sources - every on-disk window across the whole manifest that references
+// it - and reports whether the result matches manifest.ChunkShaList[guid].
+// It reports false if any window is missing, short, or unreadable, so a
+// partially-covered chunk never looks like a match.
+func (d *Downloader) chunkMatchesOnDisk(guid string, manifest *Manifest, sources map[string][]chunkSource) bool {
+	expected := manifest.ChunkShaList[guid]
+	if len(expected) == 0 {
+		return false
+	}
+
+	size := int64(manifest.ChunkFilesizeList[guid])
+	if size == 0 {
+		return false
+	}
+
+	buf := make([]byte, size)
+	covered := make([]bool, size)
+
+	for _, src := range sources[guid] {
+		if src.chunkOffset < 0 || src.chunkOffset+src.size > size {
+			return false
+		}
+
+		if !readChunkWindow(filepath.Join(d.InstallPath, src.fileName), src.fileOffset, buf[src.chunkOffset:src.chunkOffset+src.size]) {
+			return false
+		}
+
+		for i := src.chunkOffset; i < src.chunkOffset+src.size; i++ {
+			covered[i] = true
+		}
+	}
+
+	for _, ok := range covered {
+		if !ok {
+			return false
+		}
+	}
+
+	sum := sha1.Sum(buf)
+	return bytes.Equal(sum[:], expected)
+}
+
+// readChunkWindow reads len(dst) bytes at offset from the file at path into
+// dst, reporting whether the read succeeded in full.
+func readChunkWindow(path string, offset int64, dst []byte) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	n, err := f.ReadAt(dst, offset)
+	return err == nil && n == len(dst)
+}
+
+// allChunkGUIDs returns the unique GUIDs referenced by file's chunk parts.
+func allChunkGUIDs(file ManifestFile) []string {
+	seen := make(map[string]bool)
+	guids := make([]string, 0, len(file.FileChunkParts))
+	for _, part := range file.FileChunkParts {
+		if !seen[part.GUID] {
+			seen[part.GUID] = true
+			guids = append(guids, part.GUID)
+		}
+	}
+	return guids
+}